@@ -0,0 +1,170 @@
+package ansi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/matjam/mecca/internal/lexer"
+)
+
+// Canonical replaces the escape sequences in ANSI output with readable
+// placeholders such as <red>, <bg blue>, <bold> and <locate 5,10>, so that
+// golden files and test failure diffs can be reviewed by eye while still
+// asserting the styling. Bells are shown as <bell>; other text, including
+// line endings, is left as it is. Sequences without a placeholder are shown
+// as <esc[...>.
+func Canonical(s string) string {
+	var sb strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\a':
+			sb.WriteString("<bell>")
+			continue
+		case 0x1b:
+		default:
+			sb.WriteByte(s[i])
+			continue
+		}
+
+		if i+1 >= len(s) || s[i+1] != '[' {
+			sb.WriteString("<esc>")
+			continue
+		}
+
+		// find the final byte of the control sequence
+		end := i + 2
+		for end < len(s) && (s[end] < 0x40 || s[end] > 0x7e) {
+			end++
+		}
+		if end == len(s) {
+			sb.WriteString("<esc[" + s[i+2:] + ">")
+			break
+		}
+
+		sb.WriteString(canonicalCSI(s[end], s[i+2:end]))
+		i = end
+	}
+
+	return sb.String()
+}
+
+func canonicalCSI(final byte, params string) string {
+	unknown := "<esc[" + params + string(final) + ">"
+	if strings.HasPrefix(params, "?") {
+		return unknown
+	}
+
+	args := parseParams(params)
+	arg := func(i, def int) int {
+		if i < len(args) && args[i] > 0 {
+			return args[i]
+		}
+		return def
+	}
+
+	switch final {
+	case 'A':
+		return fmt.Sprintf("<up %d>", arg(0, 1))
+	case 'B':
+		return fmt.Sprintf("<down %d>", arg(0, 1))
+	case 'C':
+		return fmt.Sprintf("<forward %d>", arg(0, 1))
+	case 'D':
+		return fmt.Sprintf("<backward %d>", arg(0, 1))
+	case 'H', 'f':
+		return fmt.Sprintf("<locate %d,%d>", arg(0, 1), arg(1, 1))
+	case 'J':
+		if arg(0, 0) == 2 {
+			return "<clear>"
+		}
+	case 'K':
+		if arg(0, 0) == 0 {
+			return "<lineclear>"
+		}
+	case 's':
+		return "<save>"
+	case 'u':
+		return "<restore>"
+	case 'm':
+		return canonicalSGR(args)
+	}
+
+	return unknown
+}
+
+var sgrNames = map[int]string{
+	0:  "reset",
+	1:  "bold",
+	2:  "faint",
+	3:  "italic",
+	4:  "underline",
+	5:  "blink",
+	6:  "blinkrapid",
+	7:  "reverse",
+	9:  "crossedout",
+	22: "no bold",
+	23: "no italic",
+	24: "no underline",
+	25: "no blink",
+	27: "no reverse",
+	29: "no crossedout",
+	39: "fg default",
+	49: "bg default",
+}
+
+func canonicalSGR(args []int) string {
+	if len(args) == 0 {
+		args = []int{0}
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+
+		var name string
+		switch {
+		case (a == 38 || a == 48) && i+2 < len(args) && args[i+1] == 5:
+			// 256 color palette
+			name = fmt.Sprintf("%s %d", extendedColorTarget(a), args[i+2])
+			i += 2
+		case (a == 38 || a == 48) && i+4 < len(args) && args[i+1] == 2:
+			// 24 bit color
+			name = fmt.Sprintf("%s #%02x%02x%02x", extendedColorTarget(a), args[i+2], args[i+3], args[i+4])
+			i += 4
+		case a == 38 || a == 48:
+			// a malformed extended color; the rest of the parameters can't be
+			// interpreted reliably, so show them as they are
+			parts := make([]string, 0, len(args)-i)
+			for _, p := range args[i:] {
+				parts = append(parts, strconv.Itoa(p))
+			}
+			name = "sgr " + strings.Join(parts, ";")
+			i = len(args)
+		case a >= 30 && a <= 37:
+			name = lexer.Color(a - 30).String()
+		case a >= 90 && a <= 97:
+			name = lexer.Color(a - 90 + 8).String()
+		case a >= 40 && a <= 47:
+			name = "bg " + lexer.Color(a-40).String()
+		case a >= 100 && a <= 107:
+			name = "bg " + lexer.Color(a-100+8).String()
+		default:
+			var ok bool
+			if name, ok = sgrNames[a]; !ok {
+				name = fmt.Sprintf("sgr %d", a)
+			}
+		}
+		sb.WriteString("<" + name + ">")
+	}
+
+	return sb.String()
+}
+
+func extendedColorTarget(a int) string {
+	if a == 38 {
+		return "fg"
+	}
+	return "bg"
+}
@@ -0,0 +1,23 @@
+package ansi
+
+import (
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	input := "\x1b[2J\x1b[5;10H\x1b[1;31;44mHi\x1b[0m\x07\r\n\x1b[3C\x1b[?25lx\x1b[m"
+	expected := "<clear><locate 5,10><bold><red><bg blue>Hi<reset><bell>\r\n<forward 3><esc[?25l>x<reset>"
+
+	if got := Canonical(input); got != expected {
+		t.Errorf("Expected %q got %q", expected, got)
+	}
+}
+
+func TestCanonicalExtendedColors(t *testing.T) {
+	input := "\x1b[38;5;196mA\x1b[48;2;10;20;30mB\x1b[1;38;5;4;48;5;250mC\x1b[38;5m"
+	expected := "<fg 196>A<bg #0a141e>B<bold><fg 4><bg 250>C<sgr 38;5>"
+
+	if got := Canonical(input); got != expected {
+		t.Errorf("Expected %q got %q", expected, got)
+	}
+}
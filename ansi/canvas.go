@@ -0,0 +1,445 @@
+package ansi
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Style is the set of SGR attributes applied to a cell. Colors are the 16
+// classic ANSI colors, 0-7 normal and 8-15 bright.
+type Style struct {
+	Fg         int
+	Bg         int
+	Bold       bool
+	Faint      bool
+	Italic     bool
+	Underline  bool
+	Blink      bool
+	Reverse    bool
+	CrossedOut bool
+}
+
+// DefaultStyle is the style of a terminal after an SGR reset: light grey on
+// black.
+var DefaultStyle = Style{Fg: 7, Bg: 0}
+
+// Cell is a single character position on a Canvas. Cells that have never been
+// drawn have a zero Rune.
+type Cell struct {
+	Rune  rune
+	Style Style
+}
+
+// Art is drawn onto a canvas of at most this many columns and rows. Cursor
+// movement is clamped to these bounds and anything drawn outside them is
+// dropped, so a hostile sequence such as ESC[999999999C can't make the canvas
+// grow without limit. A canvas with a wrap width never exceeds that width.
+// When art doesn't fit, Clipped reports it so that callers don't mistake the
+// clipped drawing for the whole of the art.
+const (
+	MAX_CANVAS_WIDTH  = 1024
+	MAX_CANVAS_HEIGHT = 100000
+)
+
+type parseState int
+
+const (
+	stateText parseState = iota
+	stateEscape
+	stateCSI
+)
+
+// Canvas is a virtual screen that ANSI art is drawn onto, so that it can be
+// measured and re-emitted independently of how the original file positioned
+// its cursor.
+type Canvas struct {
+	width   int      // the column at which text wraps, or 0 for no wrapping
+	rows    [][]Cell // the drawn cells, grown on demand
+	x, y    int      // the cursor position
+	savedX  int      // the cursor column saved by ESC[s
+	savedY  int      // the cursor row saved by ESC[s
+	wrap    bool     // whether the next printable character wraps first
+	style   Style    // the current style
+	state   parseState
+	params  strings.Builder // the parameters of the escape sequence being parsed
+	stopped bool            // whether an EOF marker has been seen
+	clipped bool            // whether anything fell outside the canvas bounds
+}
+
+// NewCanvas returns an empty Canvas that wraps text at width columns. A width
+// of 0 disables wrapping, which is useful for measuring art.
+func NewCanvas(width int) *Canvas {
+	return &Canvas{
+		width: width,
+		style: DefaultStyle,
+	}
+}
+
+// WriteString draws s onto the canvas, interpreting control characters and
+// ANSI escape sequences. Escape sequences may be split across calls. Anything
+// after a DOS EOF marker (0x1A) is ignored.
+func (c *Canvas) WriteString(s string) {
+	for _, r := range s {
+		if c.stopped {
+			return
+		}
+
+		switch c.state {
+		case stateText:
+			c.text(r)
+		case stateEscape:
+			if r == '[' {
+				c.params.Reset()
+				c.state = stateCSI
+			} else {
+				// we don't support any other escape sequences, so skip them
+				c.state = stateText
+			}
+		case stateCSI:
+			if r >= 0x40 && r <= 0x7e {
+				c.csi(r, c.params.String())
+				c.state = stateText
+			} else {
+				c.params.WriteRune(r)
+			}
+		}
+	}
+}
+
+func (c *Canvas) text(r rune) {
+	switch r {
+	case 0x1b:
+		c.state = stateEscape
+	case 0x1a:
+		c.stopped = true
+	case '\r':
+		c.x = 0
+		c.wrap = false
+	case '\n':
+		c.moveTo(0, c.y+1)
+	case '\t':
+		c.moveTo((c.x/8+1)*8, c.y)
+	case '\b':
+		c.moveTo(c.x-1, c.y)
+	default:
+		if r < 0x20 {
+			// bells and other controls don't draw anything
+			return
+		}
+		c.put(r)
+	}
+}
+
+// put draws r at the cursor and advances it. Like most terminals the wrap is
+// deferred until the next character is drawn, so art that fills a line and
+// then sends CR LF doesn't get a blank line.
+func (c *Canvas) put(r rune) {
+	if c.wrap {
+		c.x = 0
+		c.y++
+		c.wrap = false
+	}
+
+	if c.x >= MAX_CANVAS_WIDTH || c.y >= MAX_CANVAS_HEIGHT {
+		c.clipped = true
+		return
+	}
+
+	for len(c.rows) <= c.y {
+		c.rows = append(c.rows, nil)
+	}
+	for len(c.rows[c.y]) <= c.x {
+		c.rows[c.y] = append(c.rows[c.y], Cell{})
+	}
+	c.rows[c.y][c.x] = Cell{Rune: r, Style: c.style}
+
+	if c.width > 0 && c.x == c.width-1 {
+		c.wrap = true
+		return
+	}
+	c.x++
+}
+
+func (c *Canvas) moveTo(x, y int) {
+	if x < 0 {
+		x = 0
+	}
+	if c.width > 0 && x > c.width-1 {
+		x = c.width - 1
+	}
+	if x > MAX_CANVAS_WIDTH {
+		x = MAX_CANVAS_WIDTH
+		c.clipped = true
+	}
+	if y < 0 {
+		y = 0
+	}
+	if y > MAX_CANVAS_HEIGHT {
+		y = MAX_CANVAS_HEIGHT
+		c.clipped = true
+	}
+	c.x, c.y = x, y
+	c.wrap = false
+}
+
+// csi handles a control sequence with the given final character.
+func (c *Canvas) csi(final rune, params string) {
+	if strings.HasPrefix(params, "?") {
+		// private modes such as cursor visibility don't affect the drawing
+		return
+	}
+
+	args := parseParams(params)
+	arg := func(i, def int) int {
+		if i < len(args) && args[i] > 0 {
+			return args[i]
+		}
+		return def
+	}
+
+	switch final {
+	case 'A':
+		c.moveTo(c.x, c.y-arg(0, 1))
+	case 'B':
+		c.moveTo(c.x, c.y+arg(0, 1))
+	case 'C':
+		c.moveTo(c.x+arg(0, 1), c.y)
+	case 'D':
+		c.moveTo(c.x-arg(0, 1), c.y)
+	case 'H', 'f':
+		c.moveTo(arg(1, 1)-1, arg(0, 1)-1)
+	case 'J':
+		if arg(0, 0) == 2 {
+			// like ANSI.SYS, clearing the screen also homes the cursor
+			c.rows = nil
+			c.moveTo(0, 0)
+		}
+	case 'K':
+		if arg(0, 0) == 0 && c.y < len(c.rows) && c.x < len(c.rows[c.y]) {
+			c.rows[c.y] = c.rows[c.y][:c.x]
+		}
+	case 's':
+		c.savedX, c.savedY = c.x, c.y
+	case 'u':
+		c.moveTo(c.savedX, c.savedY)
+	case 'm':
+		c.sgr(args)
+	}
+}
+
+// sgr applies Select Graphic Rendition parameters to the current style.
+func (c *Canvas) sgr(args []int) {
+	if len(args) == 0 {
+		args = []int{0}
+	}
+
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; {
+		case a == 0:
+			c.style = DefaultStyle
+		case a == 1:
+			c.style.Bold = true
+		case a == 2:
+			c.style.Faint = true
+		case a == 3:
+			c.style.Italic = true
+		case a == 4:
+			c.style.Underline = true
+		case a == 5 || a == 6:
+			c.style.Blink = true
+		case a == 7:
+			c.style.Reverse = true
+		case a == 9:
+			c.style.CrossedOut = true
+		case a == 22:
+			c.style.Bold = false
+			c.style.Faint = false
+		case a == 23:
+			c.style.Italic = false
+		case a == 24:
+			c.style.Underline = false
+		case a == 25:
+			c.style.Blink = false
+		case a == 27:
+			c.style.Reverse = false
+		case a == 29:
+			c.style.CrossedOut = false
+		case a >= 30 && a <= 37:
+			c.style.Fg = a - 30
+		case a == 39:
+			c.style.Fg = DefaultStyle.Fg
+		case a >= 40 && a <= 47:
+			c.style.Bg = a - 40
+		case a == 49:
+			c.style.Bg = DefaultStyle.Bg
+		case a >= 90 && a <= 97:
+			c.style.Fg = a - 90 + 8
+		case a >= 100 && a <= 107:
+			c.style.Bg = a - 100 + 8
+		case a == 38 || a == 48:
+			// extended colors; only the 16 classic colors of the 256 color
+			// palette can be represented, anything else is skipped. A
+			// truncated sequence such as ESC[38;5m is malformed, so the rest
+			// of the parameters are ignored rather than read as attributes.
+			switch {
+			case i+2 < len(args) && args[i+1] == 5:
+				if n := args[i+2]; n < 16 {
+					if a == 38 {
+						c.style.Fg = n
+					} else {
+						c.style.Bg = n
+					}
+				}
+				i += 2
+			case i+4 < len(args) && args[i+1] == 2:
+				i += 4
+			default:
+				return
+			}
+		}
+	}
+}
+
+// parseParams splits the numeric parameters of a control sequence. Missing
+// parameters are returned as 0.
+func parseParams(params string) []int {
+	if params == "" {
+		return nil
+	}
+
+	fields := strings.Split(params, ";")
+	args := make([]int, len(fields))
+	for i, f := range fields {
+		args[i], _ = strconv.Atoi(f)
+	}
+	return args
+}
+
+// Clipped returns true if the art drawn so far didn't fit within
+// MAX_CANVAS_WIDTH and MAX_CANVAS_HEIGHT, in which case the canvas only holds
+// the part of it that did.
+func (c *Canvas) Clipped() bool {
+	return c.clipped
+}
+
+// Width returns the number of columns used by the widest row.
+func (c *Canvas) Width() int {
+	width := 0
+	for _, row := range c.rows {
+		if n := rowLength(row); n > width {
+			width = n
+		}
+	}
+	return width
+}
+
+// Height returns the number of rows up to and including the last row that has
+// anything drawn on it.
+func (c *Canvas) Height() int {
+	for y := len(c.rows) - 1; y >= 0; y-- {
+		if rowLength(c.rows[y]) > 0 {
+			return y + 1
+		}
+	}
+	return 0
+}
+
+// Row returns the cells of row y, up to the last drawn cell.
+func (c *Canvas) Row(y int) []Cell {
+	if y < 0 || y >= len(c.rows) {
+		return nil
+	}
+	return c.rows[y][:rowLength(c.rows[y])]
+}
+
+func rowLength(row []Cell) int {
+	for x := len(row) - 1; x >= 0; x-- {
+		if row[x].Rune != 0 {
+			return x + 1
+		}
+	}
+	return 0
+}
+
+// String returns the canvas as ANSI text, one CR LF terminated line per row,
+// with SGR sequences emitted only where the style changes. Cells that were
+// never drawn are emitted as spaces in the default style.
+func (c *Canvas) String() string {
+	var sb strings.Builder
+
+	current := DefaultStyle
+	for y := 0; y < c.Height(); y++ {
+		for _, cell := range c.Row(y) {
+			r, style := cell.Rune, cell.Style
+			if r == 0 {
+				r, style = ' ', DefaultStyle
+			}
+			if style != current {
+				sb.WriteString(SGR(current, style))
+				current = style
+			}
+			sb.WriteRune(r)
+		}
+		if current != DefaultStyle {
+			sb.WriteString("\x1b[0m")
+			current = DefaultStyle
+		}
+		sb.WriteString("\r\n")
+	}
+
+	return sb.String()
+}
+
+// SGR returns the escape sequence that changes the terminal from style from to
+// style to, or an empty string if they are the same. Attributes can only be
+// turned off individually on some terminals, so when any attribute is removed
+// the sequence resets and sets the full style.
+func SGR(from, to Style) string {
+	if from == to {
+		return ""
+	}
+
+	var codes []string
+
+	if (from.Bold && !to.Bold) || (from.Faint && !to.Faint) || (from.Italic && !to.Italic) ||
+		(from.Underline && !to.Underline) || (from.Blink && !to.Blink) ||
+		(from.Reverse && !to.Reverse) || (from.CrossedOut && !to.CrossedOut) {
+		codes = append(codes, "0")
+		from = DefaultStyle
+	}
+
+	flags := []struct {
+		from, to bool
+		code     string
+	}{
+		{from.Bold, to.Bold, "1"},
+		{from.Faint, to.Faint, "2"},
+		{from.Italic, to.Italic, "3"},
+		{from.Underline, to.Underline, "4"},
+		{from.Blink, to.Blink, "5"},
+		{from.Reverse, to.Reverse, "7"},
+		{from.CrossedOut, to.CrossedOut, "9"},
+	}
+	for _, f := range flags {
+		if f.to && !f.from {
+			codes = append(codes, f.code)
+		}
+	}
+
+	if to.Fg != from.Fg {
+		if to.Fg < 8 {
+			codes = append(codes, strconv.Itoa(30+to.Fg))
+		} else {
+			codes = append(codes, strconv.Itoa(90+to.Fg-8))
+		}
+	}
+	if to.Bg != from.Bg {
+		if to.Bg < 8 {
+			codes = append(codes, strconv.Itoa(40+to.Bg))
+		} else {
+			codes = append(codes, strconv.Itoa(100+to.Bg-8))
+		}
+	}
+
+	return "\x1b[" + strings.Join(codes, ";") + "m"
+}
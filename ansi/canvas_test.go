@@ -0,0 +1,134 @@
+package ansi
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCanvasMeasure(t *testing.T) {
+	type test struct {
+		name   string
+		input  string
+		width  int
+		height int
+	}
+
+	tests := []test{
+		{"plain lines", "hello\r\nworld!\r\n", 6, 2},
+		{"cursor forward", "ab\x1b[10Ccd", 14, 1},
+		{"position", "\x1b[5;20Hx", 20, 5},
+		{"trailing blank lines", "a\r\n\r\n\r\n", 1, 1},
+		{"clear screen", "lots of text\r\nmore\x1b[2Jab", 2, 1},
+		{"EOF marker", "abc\x1aignored text after the marker", 3, 1},
+	}
+
+	for _, tc := range tests {
+		c := NewCanvas(0)
+		c.WriteString(tc.input)
+
+		if c.Width() != tc.width || c.Height() != tc.height {
+			t.Errorf("%s: expected %vx%v got %vx%v", tc.name, tc.width, tc.height, c.Width(), c.Height())
+		}
+	}
+}
+
+func TestCanvasWrap(t *testing.T) {
+	c := NewCanvas(4)
+
+	// a line that exactly fills the width followed by CR LF must not produce
+	// a blank line, but a line longer than the width wraps.
+	c.WriteString("abcd\r\nefghij")
+
+	expected := []string{"abcd", "efgh", "ij"}
+	if c.Height() != len(expected) {
+		t.Fatalf("Expected %v rows got %v", len(expected), c.Height())
+	}
+
+	for y, want := range expected {
+		got := ""
+		for _, cell := range c.Row(y) {
+			got += string(cell.Rune)
+		}
+		if got != want {
+			t.Errorf("row %v: expected %q got %q", y, want, got)
+		}
+	}
+}
+
+func TestCanvasStyle(t *testing.T) {
+	c := NewCanvas(0)
+	c.WriteString("\x1b[1;31;44mA\x1b[0mB\x1b[93mC")
+
+	expected := []Style{
+		{Fg: 1, Bg: 4, Bold: true},
+		DefaultStyle,
+		{Fg: 11, Bg: 0},
+	}
+
+	for x, style := range expected {
+		if got := c.Row(0)[x].Style; got != style {
+			t.Errorf("cell %v: expected %+v got %+v", x, style, got)
+		}
+	}
+
+	// truncated extended colors must not be read as other attributes
+	for _, input := range []string{"\x1b[38;5mX", "\x1b[48;5mX", "\x1b[38;2;5;1mX", "\x1b[38mX"} {
+		c := NewCanvas(0)
+		c.WriteString(input)
+		if got := c.Row(0)[0].Style; got != DefaultStyle {
+			t.Errorf("%q: expected %+v got %+v", input, DefaultStyle, got)
+		}
+	}
+}
+
+func TestCanvasString(t *testing.T) {
+	c := NewCanvas(0)
+	c.WriteString("\x1b[31mred\x1b[31m red\x1b[0m plain\r\n\x1b[3Cx")
+
+	expected := "\x1b[31mred red\x1b[37m plain\r\n   x\r\n"
+	if got := c.String(); got != expected {
+		t.Errorf("Expected %q got %q", expected, got)
+	}
+}
+
+func TestSGR(t *testing.T) {
+	type test struct {
+		from, to Style
+		expected string
+	}
+
+	tests := []test{
+		{DefaultStyle, DefaultStyle, ""},
+		{DefaultStyle, Style{Fg: 1, Bg: 0}, "\x1b[31m"},
+		{DefaultStyle, Style{Fg: 7, Bg: 9, Bold: true}, "\x1b[1;101m"},
+		{Style{Fg: 2, Bg: 0, Bold: true}, Style{Fg: 2, Bg: 0}, "\x1b[0;32m"},
+		{Style{Fg: 2, Bg: 4}, DefaultStyle, "\x1b[37;40m"},
+	}
+
+	for _, tc := range tests {
+		if got := SGR(tc.from, tc.to); got != tc.expected {
+			t.Errorf("SGR(%+v, %+v): expected %q got %q", tc.from, tc.to, tc.expected, got)
+		}
+	}
+}
+
+func TestCanvasBounds(t *testing.T) {
+	c := NewCanvas(0)
+	c.WriteString("a\x1b[999999999Cb\x1b[99999999Bc\x1b[999999999;999999999Hd")
+
+	if c.Width() > MAX_CANVAS_WIDTH || c.Height() > MAX_CANVAS_HEIGHT {
+		t.Errorf("Expected canvas within %vx%v, got %vx%v", MAX_CANVAS_WIDTH, MAX_CANVAS_HEIGHT, c.Width(), c.Height())
+	}
+	if len(c.Row(0)) != 1 {
+		t.Errorf("Expected text beyond the last column to be dropped, got %v cells", len(c.Row(0)))
+	}
+	if !c.Clipped() {
+		t.Errorf("Expected the canvas to report that it was clipped")
+	}
+
+	c = NewCanvas(0)
+	c.WriteString(strings.Repeat("x", MAX_CANVAS_WIDTH) + "\r\n\x1b[999D\x1b[999A")
+	if c.Clipped() {
+		t.Errorf("Expected art that fits not to be clipped")
+	}
+}
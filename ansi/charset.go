@@ -0,0 +1,120 @@
+package ansi
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+type Charset int
+
+const (
+	CHARSET_CP437 Charset = iota
+	CHARSET_UTF8
+	CHARSET_ASCII
+)
+
+var charsetNames = map[Charset]string{
+	CHARSET_CP437: "cp437",
+	CHARSET_UTF8:  "utf-8",
+	CHARSET_ASCII: "ascii",
+}
+
+var charsetValues = map[string]Charset{
+	"cp437":  CHARSET_CP437,
+	"ibm437": CHARSET_CP437,
+	"utf-8":  CHARSET_UTF8,
+	"utf8":   CHARSET_UTF8,
+	"ascii":  CHARSET_ASCII,
+}
+
+func (c Charset) String() string {
+	if name, ok := charsetNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("Charset(%d)", c)
+}
+
+// ParseCharset returns the Charset with the given name, ignoring case.
+func ParseCharset(name string) (Charset, error) {
+	if c, ok := charsetValues[strings.ToLower(name)]; ok {
+		return c, nil
+	}
+	return 0, fmt.Errorf("unknown charset '%s'", name)
+}
+
+// cp437 maps the upper half of code page 437 to unicode. The lower half is
+// ASCII; the control characters are left alone because in ANSI art they are
+// controls (ESC, CR, LF) rather than the glyphs DOS would draw for them.
+var cp437 = [128]rune{
+	'Ç', 'ü', 'é', 'â', 'ä', 'à', 'å', 'ç', 'ê', 'ë', 'è', 'ï', 'î', 'ì', 'Ä', 'Å',
+	'É', 'æ', 'Æ', 'ô', 'ö', 'ò', 'û', 'ù', 'ÿ', 'Ö', 'Ü', '¢', '£', '¥', '₧', 'ƒ',
+	'á', 'í', 'ó', 'ú', 'ñ', 'Ñ', 'ª', 'º', '¿', '⌐', '¬', '½', '¼', '¡', '«', '»',
+	'░', '▒', '▓', '│', '┤', '╡', '╢', '╖', '╕', '╣', '║', '╗', '╝', '╜', '╛', '┐',
+	'└', '┴', '┬', '├', '─', '┼', '╞', '╟', '╚', '╔', '╩', '╦', '╠', '═', '╬', '╧',
+	'╨', '╤', '╥', '╙', '╘', '╒', '╓', '╫', '╪', '┘', '┌', '█', '▄', '▌', '▐', '▀',
+	'α', 'ß', 'Γ', 'π', 'Σ', 'σ', 'µ', 'τ', 'Φ', 'Θ', 'Ω', 'δ', '∞', 'φ', 'ε', '∩',
+	'≡', '±', '≥', '≤', '⌠', '⌡', '÷', '≈', '°', '∙', '·', '√', 'ⁿ', '²', '■', '\u00a0',
+}
+
+var cp437Bytes = func() map[rune]byte {
+	m := make(map[rune]byte, len(cp437)+1)
+	for i, r := range cp437 {
+		m[r] = byte(0x80 + i)
+	}
+	m['⌂'] = 0x7f
+	return m
+}()
+
+// Decode converts data in the given charset to a UTF-8 string.
+func Decode(data []byte, from Charset) (string, error) {
+	switch from {
+	case CHARSET_UTF8:
+		return string(data), nil
+	case CHARSET_ASCII:
+		return Transliterate(string(data)), nil
+	case CHARSET_CP437:
+		var sb strings.Builder
+		sb.Grow(len(data))
+		for _, b := range data {
+			switch {
+			case b == 0x7f:
+				sb.WriteRune('⌂')
+			case b >= 0x80:
+				sb.WriteRune(cp437[b-0x80])
+			default:
+				sb.WriteByte(b)
+			}
+		}
+		return sb.String(), nil
+	}
+	return "", fmt.Errorf("cannot decode from %v", from)
+}
+
+// Encode converts a UTF-8 string to the given charset. When encoding to ASCII,
+// characters are transliterated to an approximation where possible. Characters
+// that have no equivalent in the target charset are replaced with '?'.
+func Encode(s string, to Charset) ([]byte, error) {
+	switch to {
+	case CHARSET_UTF8:
+		return []byte(s), nil
+	case CHARSET_ASCII:
+		return []byte(Transliterate(s)), nil
+	case CHARSET_CP437:
+		out := make([]byte, 0, len(s))
+		for _, r := range s {
+			switch {
+			case r < utf8.RuneSelf:
+				out = append(out, byte(r))
+			default:
+				b, ok := cp437Bytes[r]
+				if !ok {
+					b = '?'
+				}
+				out = append(out, b)
+			}
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("cannot encode to %v", to)
+}
@@ -0,0 +1,33 @@
+package ansi
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCharsetRoundTrip(t *testing.T) {
+	input := []byte{'H', 'i', ' ', 0xb0, 0xb1, 0xb2, 0xdb, 0xc9, 0xcd, 0xbb, 0x1b, '[', 'm', 0xff}
+
+	s, err := Decode(input, CHARSET_CP437)
+	if err != nil {
+		t.Fatalf("Error decoding: %s", err)
+	}
+
+	expected := "Hi ░▒▓█╔═╗\x1b[m "
+	if s != expected {
+		t.Errorf("Expected %q got %q", expected, s)
+	}
+
+	output, err := Encode(s, CHARSET_CP437)
+	if err != nil {
+		t.Fatalf("Error encoding: %s", err)
+	}
+	if !bytes.Equal(output, input) {
+		t.Errorf("Expected %v got %v", input, output)
+	}
+
+	output, _ = Encode("snow ☃", CHARSET_CP437)
+	if string(output) != "snow ?" {
+		t.Errorf("Expected unmappable characters to be replaced, got %q", output)
+	}
+}
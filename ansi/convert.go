@@ -0,0 +1,104 @@
+package ansi
+
+import "fmt"
+
+var errCanvasClipped = fmt.Errorf("art is larger than %vx%v", MAX_CANVAS_WIDTH, MAX_CANVAS_HEIGHT)
+
+// ConvertOptions controls how Convert processes an art file.
+type ConvertOptions struct {
+	From        Charset // the charset of the input
+	To          Charset // the charset of the output
+	Width       int     // if set, re-wrap the art to this many columns
+	StripSauce  bool    // drop the SAUCE record from the output
+	RepairSauce bool    // correct the size and dimensions in the SAUCE record
+}
+
+// Convert converts an art file between charsets and optionally re-wraps it to
+// a fixed width. Re-wrapping draws the art on a Canvas and emits each row as
+// an explicit CR LF terminated line, so the result no longer depends on the
+// terminal wrapping at the width the artist used.
+//
+// The SAUCE record, if any, is carried over unless StripSauce is set. With
+// RepairSauce the record's file size is corrected, and when re-wrapping its
+// width and height are updated to the size of the re-wrapped art.
+//
+// Width may be at most MAX_CANVAS_WIDTH, and re-wrapped art must fit on a
+// canvas of that size; anything larger is an error rather than being cut off.
+func Convert(data []byte, opts ConvertOptions) ([]byte, error) {
+	if opts.Width > MAX_CANVAS_WIDTH {
+		return nil, fmt.Errorf("width %v exceeds %v", opts.Width, MAX_CANVAS_WIDTH)
+	}
+
+	content, sauce := SplitSauce(data)
+
+	text, err := Decode(content, opts.From)
+	if err != nil {
+		return nil, err
+	}
+
+	var canvas *Canvas
+	if opts.Width > 0 {
+		canvas = NewCanvas(opts.Width)
+		canvas.WriteString(text)
+		if canvas.Clipped() {
+			return nil, errCanvasClipped
+		}
+		text = canvas.String()
+	}
+
+	out, err := Encode(text, opts.To)
+	if err != nil {
+		return nil, err
+	}
+
+	if sauce == nil || opts.StripSauce {
+		return out, nil
+	}
+
+	if opts.RepairSauce {
+		sauce.FileSize = uint32(len(out))
+		if canvas != nil && sauce.DataType == SAUCE_DATATYPE_CHARACTER {
+			// the canvas bounds keep both of these well within a uint16
+			sauce.TInfo1 = uint16(canvas.Width())
+			sauce.TInfo2 = uint16(canvas.Height())
+		}
+	}
+
+	return AppendSauce(out, sauce)
+}
+
+// Measure returns the width and height of an art file in the given charset.
+// If the file has a SAUCE record that records its width, the art is wrapped
+// at that width as it would be on a terminal of that size; otherwise lines are
+// measured as they are, however long.
+func Measure(data []byte, from Charset) (width, height int, err error) {
+	_, sauce := SplitSauce(data)
+
+	wrap := 0
+	if sauce != nil {
+		wrap = sauce.Width()
+	}
+
+	return MeasureWrapped(data, from, wrap)
+}
+
+// MeasureWrapped returns the width and height of an art file in the given
+// charset when drawn on a terminal that wraps at wrap columns, or doesn't wrap
+// at all if wrap is 0. This is for art whose SAUCE record has been stripped
+// but which still depends on the width it recorded. Any SAUCE record in data
+// is ignored. Art that doesn't fit on a Canvas is an error, since its size
+// can't be measured.
+func MeasureWrapped(data []byte, from Charset, wrap int) (width, height int, err error) {
+	text, err := Decode(StripSauce(data), from)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	canvas := NewCanvas(wrap)
+	canvas.WriteString(text)
+	if canvas.Clipped() {
+		return 0, 0, errCanvasClipped
+	}
+
+	return canvas.Width(), canvas.Height(), nil
+}
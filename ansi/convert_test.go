@@ -0,0 +1,83 @@
+package ansi
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	sauce := &Sauce{DataType: SAUCE_DATATYPE_CHARACTER, FileType: SAUCE_FILETYPE_ANSI, TInfo1: 8, FileSize: 999}
+	input, _ := AppendSauce([]byte{0xdb, 0xdb, 0xdb, 0xdb, 0xdb, 0xdb, 0xdb, 0xdb, 0xdb, 0xdb}, sauce)
+
+	output, err := Convert(input, ConvertOptions{
+		From:        CHARSET_CP437,
+		To:          CHARSET_UTF8,
+		Width:       4,
+		RepairSauce: true,
+	})
+	if err != nil {
+		t.Fatalf("Error converting: %s", err)
+	}
+
+	content, got := SplitSauce(output)
+	expected := "████\r\n████\r\n██\r\n"
+	if string(content) != expected {
+		t.Errorf("Expected %q got %q", expected, content)
+	}
+	if got == nil || got.FileSize != uint32(len(expected)) || got.TInfo1 != 4 || got.TInfo2 != 3 {
+		t.Errorf("Expected repaired SAUCE record, got %+v", got)
+	}
+
+	w, h, err := Measure(output, CHARSET_UTF8)
+	if err != nil || w != 4 || h != 3 {
+		t.Errorf("Expected 4x3 got %vx%v (%v)", w, h, err)
+	}
+
+	// the repaired width is the width of the output, not the one requested
+	output, _ = Convert(input, ConvertOptions{From: CHARSET_CP437, To: CHARSET_UTF8, Width: 80, RepairSauce: true})
+	if _, got = SplitSauce(output); got == nil || got.TInfo1 != 10 || got.TInfo2 != 1 {
+		t.Errorf("Expected a 10x1 SAUCE record, got %+v", got)
+	}
+
+	output, _ = Convert(input, ConvertOptions{From: CHARSET_CP437, To: CHARSET_CP437, StripSauce: true})
+	if len(output) != 10 {
+		t.Errorf("Expected SAUCE to be stripped, got %q", output)
+	}
+}
+
+func TestMeasureWrapped(t *testing.T) {
+	sauce := &Sauce{DataType: SAUCE_DATATYPE_CHARACTER, FileType: SAUCE_FILETYPE_ANSI, TInfo1: 80}
+	input, _ := AppendSauce(bytes.Repeat([]byte{'x'}, 240), sauce)
+
+	if w, h, _ := Measure(input, CHARSET_CP437); w != 80 || h != 3 {
+		t.Errorf("Expected 80x3 got %vx%v", w, h)
+	}
+
+	stripped := StripSauce(input)
+	if w, h, _ := Measure(stripped, CHARSET_CP437); w != 240 || h != 1 {
+		t.Errorf("Expected 240x1 without the SAUCE width, got %vx%v", w, h)
+	}
+	if w, h, _ := MeasureWrapped(stripped, CHARSET_CP437, 80); w != 80 || h != 3 {
+		t.Errorf("Expected 80x3 got %vx%v", w, h)
+	}
+}
+
+func TestConvertTooWide(t *testing.T) {
+	sauce := &Sauce{DataType: SAUCE_DATATYPE_CHARACTER, FileType: SAUCE_FILETYPE_ANSI, TInfo1: 1500}
+	input, _ := AppendSauce(bytes.Repeat([]byte{'x'}, 1500), sauce)
+
+	if _, err := Convert(input, ConvertOptions{Width: 2000, RepairSauce: true}); err == nil {
+		t.Errorf("Expected an error re-wrapping wider than %v", MAX_CANVAS_WIDTH)
+	}
+	if w, h, err := Measure(input, CHARSET_CP437); err == nil {
+		t.Errorf("Expected an error measuring 1500 columns, got %vx%v", w, h)
+	}
+
+	output, err := Convert(input, ConvertOptions{Width: 1000})
+	if err != nil {
+		t.Fatalf("Error converting: %s", err)
+	}
+	if w, h, err := MeasureWrapped(output, CHARSET_CP437, 0); err != nil || w != 1000 || h != 2 {
+		t.Errorf("Expected 1000x2 got %vx%v (%v)", w, h, err)
+	}
+}
@@ -0,0 +1,111 @@
+package ansi
+
+import (
+	"strings"
+
+	"github.com/matjam/mecca/internal/lexer"
+)
+
+// DefaultWidth is the width classic ANSI art is drawn for when its SAUCE
+// record doesn't say otherwise.
+const DefaultWidth = 80
+
+// Decompile converts an ANSI art file into equivalent MECCA source, so that
+// the screen can be edited as a template. The art is drawn on a Canvas at the
+// width from its SAUCE record, or DefaultWidth, and each row is emitted as a
+// line of text with [fg], [bg] and attribute commands wherever the style
+// changes. Literal brackets are escaped.
+func Decompile(data []byte, from Charset) (string, error) {
+	content, sauce := SplitSauce(data)
+
+	text, err := Decode(content, from)
+	if err != nil {
+		return "", err
+	}
+
+	width := DefaultWidth
+	if sauce != nil && sauce.Width() > 0 {
+		width = sauce.Width()
+	}
+
+	canvas := NewCanvas(width)
+	canvas.WriteString(text)
+
+	var sb strings.Builder
+
+	current := DefaultStyle
+	for y := 0; y < canvas.Height(); y++ {
+		for _, cell := range canvas.Row(y) {
+			r, style := cell.Rune, cell.Style
+			if r == 0 {
+				r, style = ' ', DefaultStyle
+			}
+			if style != current && !(r == ' ' && blankAlike(current, style)) {
+				sb.WriteString(meccaStyle(current, style))
+				current = style
+			}
+			if r == '[' {
+				sb.WriteString("[[")
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	if current != DefaultStyle {
+		sb.WriteString("[reset]")
+	}
+
+	return sb.String(), nil
+}
+
+// blankAlike reports whether a space looks the same in both styles, so that
+// runs of spaces don't pick up color changes that only matter to the text
+// around them.
+func blankAlike(a, b Style) bool {
+	if a.Bg != b.Bg || a.Reverse != b.Reverse || a.Underline != b.Underline || a.CrossedOut != b.CrossedOut {
+		return false
+	}
+	// reversed spaces are drawn in the foreground color
+	return !a.Reverse || a.Fg == b.Fg
+}
+
+// meccaStyle returns the MECCA commands that change the style from from to to.
+func meccaStyle(from, to Style) string {
+	var sb strings.Builder
+
+	if (from.Bold && !to.Bold) || (from.Faint && !to.Faint) || (from.Italic && !to.Italic) ||
+		(from.Underline && !to.Underline) || (from.Blink && !to.Blink) ||
+		(from.Reverse && !to.Reverse) || (from.CrossedOut && !to.CrossedOut) {
+		sb.WriteString("[reset]")
+		from = DefaultStyle
+	}
+
+	flags := []struct {
+		from, to bool
+		command  string
+	}{
+		{from.Bold, to.Bold, "[bold]"},
+		{from.Faint, to.Faint, "[faint]"},
+		{from.Italic, to.Italic, "[italic]"},
+		{from.Underline, to.Underline, "[underline]"},
+		{from.Blink, to.Blink, "[blink]"},
+		{from.Reverse, to.Reverse, "[reverse]"},
+		{from.CrossedOut, to.CrossedOut, "[crossedout]"},
+	}
+	for _, f := range flags {
+		if f.to && !f.from {
+			sb.WriteString(f.command)
+		}
+	}
+
+	if to.Fg != from.Fg {
+		sb.WriteString("[fg " + lexer.Color(to.Fg).String() + "]")
+	}
+	if to.Bg != from.Bg {
+		sb.WriteString("[bg " + lexer.Color(to.Bg).String() + "]")
+	}
+
+	return sb.String()
+}
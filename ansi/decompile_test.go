@@ -0,0 +1,37 @@
+package ansi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/matjam/mecca/internal/lexer"
+)
+
+func TestDecompile(t *testing.T) {
+	input := []byte("\x1b[1;31mHi\x1b[0m [[x] \x1b[32m \x1b[44mbox\r\n\x1b[2Cnext")
+
+	output, err := Decompile(input, CHARSET_CP437)
+	if err != nil {
+		t.Fatalf("Error decompiling: %s", err)
+	}
+
+	expected := "[bold][fg red]Hi [reset][[[[x]  [fg green][bg blue]box\n[fg white][bg black]  [fg green][bg blue]next\n[reset]"
+	if output != expected {
+		t.Errorf("Expected %q got %q", expected, output)
+	}
+
+	// the result must be valid MECCA that the lexer can tokenize
+	l := lexer.NewLexer(strings.NewReader(output))
+	for {
+		token, err := l.Next()
+		if err != nil {
+			t.Fatalf("Error lexing decompiled output: %s", err)
+		}
+		if token.Type == lexer.TOKEN_EOF {
+			break
+		}
+		if token.Type == lexer.TOKEN_COMMAND {
+			t.Errorf("Unexpected unknown command %v", token)
+		}
+	}
+}
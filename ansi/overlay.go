@@ -0,0 +1,140 @@
+package ansi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Field is a region of an art file that a value is painted onto, such as the
+// space on a welcome screen where the caller's name goes.
+type Field struct {
+	Name  string `json:"name"`  // the name of the value to paint
+	Row   int    `json:"row"`   // the row of the first character, starting at 1
+	Col   int    `json:"col"`   // the column of the first character, starting at 1
+	Width int    `json:"width"` // if set, values are truncated or padded to this width
+	Align string `json:"align"` // "left" (the default), "right" or "center"
+}
+
+// LoadFields reads a field map: a JSON array of Fields.
+func LoadFields(r io.Reader) ([]Field, error) {
+	var fields []Field
+	if err := json.NewDecoder(r).Decode(&fields); err != nil {
+		return nil, fmt.Errorf("invalid field map: %w", err)
+	}
+
+	for _, f := range fields {
+		if f.Name == "" || f.Row < 1 || f.Col < 1 {
+			return nil, fmt.Errorf("invalid field map: field '%s' at %v:%v", f.Name, f.Row, f.Col)
+		}
+		switch f.Align {
+		case "", "left", "right", "center":
+		default:
+			return nil, fmt.Errorf("invalid field map: field '%s' has unknown alignment '%s'", f.Name, f.Align)
+		}
+	}
+
+	return fields, nil
+}
+
+// Paint draws values onto the canvas at the positions given by fields. Each
+// character takes the style of the cell it replaces, so the art decides how
+// the value is colored. Control characters are removed from values before
+// they are painted, and values are cut off at the right edge of the canvas so
+// they never make the art wrap. Fields with no value, or that lie outside the
+// canvas, are left as drawn.
+func (c *Canvas) Paint(fields []Field, values map[string]string) {
+	for _, f := range fields {
+		value, ok := values[f.Name]
+		if !ok {
+			continue
+		}
+
+		y, x := f.Row-1, f.Col-1
+		if y < 0 || x < 0 || y >= MAX_CANVAS_HEIGHT {
+			continue
+		}
+
+		value = alignField(sanitizeField(value), f.Width, f.Align)
+
+		for len(c.rows) <= y {
+			c.rows = append(c.rows, nil)
+		}
+
+		// cells the art never drew take the style of the start of the field
+		style := DefaultStyle
+		if x < len(c.rows[y]) && c.rows[y][x].Rune != 0 {
+			style = c.rows[y][x].Style
+		}
+
+		for _, r := range value {
+			if x >= MAX_CANVAS_WIDTH || (c.width > 0 && x >= c.width) {
+				break
+			}
+			for len(c.rows[y]) <= x {
+				c.rows[y] = append(c.rows[y], Cell{})
+			}
+			if c.rows[y][x].Rune != 0 {
+				style = c.rows[y][x].Style
+			}
+			c.rows[y][x] = Cell{Rune: r, Style: style}
+			x++
+		}
+	}
+}
+
+// sanitizeField removes control characters from a value, so that caller data
+// such as a user name can't inject escape sequences or line breaks into the
+// art. C1 controls are removed too, as some terminals act on them in UTF-8.
+func sanitizeField(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || (r >= 0x7f && r <= 0x9f) {
+			return -1
+		}
+		return r
+	}, value)
+}
+
+func alignField(value string, width int, align string) string {
+	if width <= 0 {
+		return value
+	}
+
+	n := utf8.RuneCountInString(value)
+	if n > width {
+		return string([]rune(value)[:width])
+	}
+
+	pad := width - n
+	switch align {
+	case "right":
+		return strings.Repeat(" ", pad) + value
+	case "center":
+		return strings.Repeat(" ", pad/2) + value + strings.Repeat(" ", pad-pad/2)
+	}
+	return value + strings.Repeat(" ", pad)
+}
+
+// Overlay draws an art file at the width from its SAUCE record, or
+// DefaultWidth, paints values onto it and returns the result as ANSI text.
+func Overlay(data []byte, from Charset, fields []Field, values map[string]string) (string, error) {
+	content, sauce := SplitSauce(data)
+
+	text, err := Decode(content, from)
+	if err != nil {
+		return "", err
+	}
+
+	width := DefaultWidth
+	if sauce != nil && sauce.Width() > 0 {
+		width = sauce.Width()
+	}
+
+	canvas := NewCanvas(width)
+	canvas.WriteString(text)
+	canvas.Paint(fields, values)
+
+	return canvas.String(), nil
+}
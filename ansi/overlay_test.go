@@ -0,0 +1,74 @@
+package ansi
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOverlay(t *testing.T) {
+	art := []byte("\x1b[34m+--------+\r\n|\x1b[33m        \x1b[34m|\r\n+--------+")
+
+	fields, err := LoadFields(strings.NewReader(`[
+		{"name": "user", "row": 2, "col": 2, "width": 8, "align": "center"},
+		{"name": "missing", "row": 1, "col": 1}
+	]`))
+	if err != nil {
+		t.Fatalf("Error loading fields: %s", err)
+	}
+
+	output, err := Overlay(art, CHARSET_CP437, fields, map[string]string{"user": "matjam"})
+	if err != nil {
+		t.Fatalf("Error painting overlay: %s", err)
+	}
+
+	expected := "<blue>+--------+<reset>\r\n<blue>|<yellow> matjam <blue>|<reset>\r\n<blue>+--------+<reset>\r\n"
+	if got := Canonical(output); got != expected {
+		t.Errorf("Expected %q got %q", expected, got)
+	}
+
+	// values are truncated to the field width
+	output, _ = Overlay(art, CHARSET_CP437, fields, map[string]string{"user": "a very long name"})
+	expected = "<blue>|<yellow>a very l<blue>|<reset>"
+	if got := Canonical(output); !strings.Contains(got, expected) {
+		t.Errorf("Expected %q in %q", expected, got)
+	}
+
+	if _, err := LoadFields(strings.NewReader(`[{"name": "x", "row": 0, "col": 1}]`)); err == nil {
+		t.Errorf("Expected an error for a field outside the art")
+	}
+}
+
+func TestOverlayHostileValue(t *testing.T) {
+	art := []byte("Hello\r\n")
+	fields := []Field{{Name: "user", Row: 1, Col: 7}}
+
+	output, err := Overlay(art, CHARSET_CP437, fields, map[string]string{"user": "\x1b[2J\x1b]0;x\a\r\nbob\u009b2J\x7f"})
+	if err != nil {
+		t.Fatalf("Error painting overlay: %s", err)
+	}
+
+	expected := "Hello [2J]0;xbob2J\r\n"
+	if output != expected {
+		t.Errorf("Expected %q got %q", expected, output)
+	}
+}
+
+func TestOverlayBounds(t *testing.T) {
+	art := []byte("\x1b[80C\r\n")
+	fields := []Field{
+		{Name: "user", Row: 1, Col: 78},
+		{Name: "user", Row: 0, Col: 1},
+		{Name: "user", Row: 1, Col: 0},
+	}
+
+	c := NewCanvas(80)
+	c.WriteString(string(art))
+	c.Paint(fields, map[string]string{"user": "longusername"})
+
+	if c.Width() != 80 {
+		t.Errorf("Expected the field to be clipped to 80 columns, got %v", c.Width())
+	}
+	if got := string([]rune{c.Row(0)[77].Rune, c.Row(0)[78].Rune, c.Row(0)[79].Rune}); got != "lon" {
+		t.Errorf("Expected %q got %q", "lon", got)
+	}
+}
@@ -0,0 +1,186 @@
+package ansi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+// SAUCE records are a fixed 128 byte trailer appended to art files, optionally
+// preceded by a comment block and an EOF (0x1A) marker. See
+// https://www.acid.org/info/sauce/sauce.htm for the format.
+const (
+	sauceRecordSize  = 128
+	sauceCommentSize = 64
+	sauceID          = "SAUCE"
+	sauceCommentID   = "COMNT"
+	sauceEOF         = 0x1A
+)
+
+// SAUCE data types, as stored in Sauce.DataType.
+const (
+	SAUCE_DATATYPE_NONE      byte = 0
+	SAUCE_DATATYPE_CHARACTER byte = 1
+)
+
+// SAUCE file types for SAUCE_DATATYPE_CHARACTER, as stored in Sauce.FileType.
+const (
+	SAUCE_FILETYPE_ASCII byte = 0
+	SAUCE_FILETYPE_ANSI  byte = 1
+)
+
+// Sauce holds the metadata from a SAUCE record.
+type Sauce struct {
+	Version  string
+	Title    string
+	Author   string
+	Group    string
+	Date     string // CCYYMMDD
+	FileSize uint32
+	DataType byte
+	FileType byte
+	TInfo1   uint16 // width in characters for character based files
+	TInfo2   uint16 // height in lines for character based files
+	TInfo3   uint16
+	TInfo4   uint16
+	Comments []string
+	TFlags   byte
+	TInfoS   string // the font name for character based files
+}
+
+// Width returns the width in characters recorded in the SAUCE record, or 0 if
+// the record does not describe character based art.
+func (s *Sauce) Width() int {
+	if s.DataType != SAUCE_DATATYPE_CHARACTER {
+		return 0
+	}
+	return int(s.TInfo1)
+}
+
+// SplitSauce separates data into the art content and its SAUCE record. If data
+// has no SAUCE record it is returned unchanged with a nil Sauce. The EOF
+// marker and comment block are not part of the returned content.
+func SplitSauce(data []byte) ([]byte, *Sauce) {
+	if len(data) < sauceRecordSize {
+		return data, nil
+	}
+
+	record := data[len(data)-sauceRecordSize:]
+	if !bytes.HasPrefix(record, []byte(sauceID)) {
+		return data, nil
+	}
+
+	s := &Sauce{
+		Version:  string(record[5:7]),
+		Title:    sauceString(record[7:42]),
+		Author:   sauceString(record[42:62]),
+		Group:    sauceString(record[62:82]),
+		Date:     sauceString(record[82:90]),
+		FileSize: binary.LittleEndian.Uint32(record[90:94]),
+		DataType: record[94],
+		FileType: record[95],
+		TInfo1:   binary.LittleEndian.Uint16(record[96:98]),
+		TInfo2:   binary.LittleEndian.Uint16(record[98:100]),
+		TInfo3:   binary.LittleEndian.Uint16(record[100:102]),
+		TInfo4:   binary.LittleEndian.Uint16(record[102:104]),
+		TFlags:   record[105],
+		TInfoS:   sauceString(record[106:128]),
+	}
+
+	content := data[:len(data)-sauceRecordSize]
+
+	// the comment block is only present if the record says so, and only if
+	// it is actually there; broken records claiming comments are common.
+	if n := int(record[104]); n > 0 {
+		size := len(sauceCommentID) + n*sauceCommentSize
+		if len(content) >= size && bytes.HasPrefix(content[len(content)-size:], []byte(sauceCommentID)) {
+			block := content[len(content)-size+len(sauceCommentID):]
+			for i := 0; i < n; i++ {
+				s.Comments = append(s.Comments, sauceString(block[i*sauceCommentSize:(i+1)*sauceCommentSize]))
+			}
+			content = content[:len(content)-size]
+		}
+	}
+
+	if len(content) > 0 && content[len(content)-1] == sauceEOF {
+		content = content[:len(content)-1]
+	}
+
+	return content, s
+}
+
+// StripSauce returns data without its SAUCE record, comment block and EOF
+// marker.
+func StripSauce(data []byte) []byte {
+	content, _ := SplitSauce(data)
+	return content
+}
+
+// MarshalBinary encodes the SAUCE record, including the comment block if
+// there are comments, but not the EOF marker.
+func (s *Sauce) MarshalBinary() ([]byte, error) {
+	if len(s.Comments) > 255 {
+		return nil, errors.New("sauce: too many comment lines")
+	}
+
+	var b bytes.Buffer
+
+	if len(s.Comments) > 0 {
+		b.WriteString(sauceCommentID)
+		for _, c := range s.Comments {
+			b.Write(saucePad(c, sauceCommentSize, ' '))
+		}
+	}
+
+	version := s.Version
+	if version == "" {
+		version = "00"
+	}
+
+	b.WriteString(sauceID)
+	b.Write(saucePad(version, 2, '0'))
+	b.Write(saucePad(s.Title, 35, ' '))
+	b.Write(saucePad(s.Author, 20, ' '))
+	b.Write(saucePad(s.Group, 20, ' '))
+	b.Write(saucePad(s.Date, 8, ' '))
+	binary.Write(&b, binary.LittleEndian, s.FileSize)
+	b.WriteByte(s.DataType)
+	b.WriteByte(s.FileType)
+	binary.Write(&b, binary.LittleEndian, []uint16{s.TInfo1, s.TInfo2, s.TInfo3, s.TInfo4})
+	b.WriteByte(byte(len(s.Comments)))
+	b.WriteByte(s.TFlags)
+	b.Write(saucePad(s.TInfoS, 22, 0))
+
+	return b.Bytes(), nil
+}
+
+// AppendSauce appends the EOF marker and the encoded SAUCE record to content.
+func AppendSauce(content []byte, s *Sauce) ([]byte, error) {
+	record, err := s.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(content)+1+len(record))
+	out = append(out, content...)
+	out = append(out, sauceEOF)
+	out = append(out, record...)
+
+	return out, nil
+}
+
+// sauceString decodes a space or NUL padded SAUCE field.
+func sauceString(b []byte) string {
+	return strings.TrimRight(string(b), " \x00")
+}
+
+// saucePad truncates or pads s to exactly n bytes.
+func saucePad(s string, n int, pad byte) []byte {
+	b := make([]byte, n)
+	copy(b, s)
+	for i := len(s); i < n; i++ {
+		b[i] = pad
+	}
+	return b
+}
@@ -0,0 +1,59 @@
+package ansi
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSauceRoundTrip(t *testing.T) {
+	sauce := &Sauce{
+		Title:    "Welcome",
+		Author:   "matjam",
+		Group:    "mecca",
+		Date:     "20231101",
+		FileSize: 5,
+		DataType: SAUCE_DATATYPE_CHARACTER,
+		FileType: SAUCE_FILETYPE_ANSI,
+		TInfo1:   80,
+		TInfo2:   25,
+		Comments: []string{"first comment", "second comment"},
+		TInfoS:   "IBM VGA",
+	}
+
+	data, err := AppendSauce([]byte("hello"), sauce)
+	if err != nil {
+		t.Fatalf("Error appending SAUCE: %s", err)
+	}
+
+	if len(data) != 5+1+5+2*64+128 {
+		t.Errorf("Unexpected encoded length %v", len(data))
+	}
+
+	content, got := SplitSauce(data)
+	if string(content) != "hello" {
+		t.Errorf("Expected content %q got %q", "hello", content)
+	}
+	if got == nil {
+		t.Fatalf("Expected a SAUCE record")
+	}
+
+	sauce.Version = "00"
+	if got.Title != sauce.Title || got.Author != sauce.Author || got.Date != sauce.Date ||
+		got.TInfo1 != 80 || got.TInfo2 != 25 || got.TInfoS != sauce.TInfoS ||
+		len(got.Comments) != 2 || got.Comments[1] != "second comment" {
+		t.Errorf("Expected %+v got %+v", sauce, got)
+	}
+
+	if string(StripSauce(data)) != "hello" {
+		t.Errorf("Expected StripSauce to remove the record")
+	}
+}
+
+func TestSplitSauceWithoutRecord(t *testing.T) {
+	data := []byte("no sauce here")
+
+	content, sauce := SplitSauce(data)
+	if sauce != nil || !bytes.Equal(content, data) {
+		t.Errorf("Expected data to be unchanged, got %q %+v", content, sauce)
+	}
+}
@@ -0,0 +1,60 @@
+package ansi
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// asciiReplacements approximates the non-ASCII characters commonly found in
+// art and templates, mostly the code page 437 repertoire, for terminals that
+// can only display ASCII. Every character is replaced by exactly one other so
+// that columns in art and box layouts stay where they were drawn.
+var asciiReplacements = map[rune]rune{
+	// shades and blocks
+	'░': '.', '▒': ':', '▓': '#', '█': '#', '▄': '#', '▀': '#', '▌': '#', '▐': '#', '■': '#',
+
+	// lines
+	'─': '-', '━': '-', '═': '=',
+	'│': '|', '┃': '|', '║': '|',
+	'┌': '+', '┐': '+', '└': '+', '┘': '+', '├': '+', '┤': '+', '┬': '+', '┴': '+', '┼': '+',
+	'╒': '+', '╓': '+', '╔': '+', '╕': '+', '╖': '+', '╗': '+', '╘': '+', '╙': '+', '╚': '+',
+	'╛': '+', '╜': '+', '╝': '+', '╞': '+', '╟': '+', '╠': '+', '╡': '+', '╢': '+', '╣': '+',
+	'╤': '+', '╥': '+', '╦': '+', '╧': '+', '╨': '+', '╩': '+', '╪': '+', '╫': '+', '╬': '+',
+	'⌐': '+', '¬': '+', '⌠': '|', '⌡': '|',
+
+	// letters
+	'Ç': 'C', 'ü': 'u', 'é': 'e', 'â': 'a', 'ä': 'a', 'à': 'a', 'å': 'a', 'ç': 'c',
+	'ê': 'e', 'ë': 'e', 'è': 'e', 'ï': 'i', 'î': 'i', 'ì': 'i', 'Ä': 'A', 'Å': 'A',
+	'É': 'E', 'æ': 'a', 'Æ': 'A', 'ô': 'o', 'ö': 'o', 'ò': 'o', 'û': 'u', 'ù': 'u',
+	'ÿ': 'y', 'Ö': 'O', 'Ü': 'U', 'á': 'a', 'í': 'i', 'ó': 'o', 'ú': 'u', 'ñ': 'n',
+	'Ñ': 'N', 'ª': 'a', 'º': 'o', 'ß': 's', 'µ': 'u', 'ⁿ': 'n', 'α': 'a', 'π': 'p',
+
+	// punctuation and symbols
+	'¿': '?', '¡': '!', '«': '<', '»': '>', '‘': '\'', '’': '\'', '“': '"', '”': '"',
+	'–': '-', '—': '-', '…': '.', '•': '*', '·': '.', '∙': '.', '°': 'o', '±': '+',
+	'÷': '/', '≈': '~', '≡': '=', '≥': '>', '≤': '<', '√': 'v', '∞': '8', '²': '2',
+	'½': '%', '¼': '%', '¢': 'c', '£': 'L', '¥': 'Y', '₧': 'P', 'ƒ': 'f', '⌂': '^',
+	'\u00a0': ' ',
+}
+
+// Transliterate replaces characters that are not ASCII with a single ASCII
+// character approximating them, or '?' when there is none, so the result has
+// as many characters as s.
+func Transliterate(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s))
+
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			sb.WriteRune(r)
+			continue
+		}
+		if replacement, ok := asciiReplacements[r]; ok {
+			sb.WriteRune(replacement)
+			continue
+		}
+		sb.WriteByte('?')
+	}
+
+	return sb.String()
+}
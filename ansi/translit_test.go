@@ -0,0 +1,31 @@
+package ansi
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTransliterate(t *testing.T) {
+	input := "╔══╗\n║ é ║\n╚══╝ ░▒▓ ☃"
+	expected := "+==+\n| e |\n+==+ .:# ?"
+
+	if got := Transliterate(input); got != expected {
+		t.Errorf("Expected %q got %q", expected, got)
+	}
+
+	output, err := Encode("┌─┐ ½", CHARSET_ASCII)
+	if err != nil || string(output) != "+-+ %" {
+		t.Errorf("Expected %q got %q (%v)", "+-+ %", output, err)
+	}
+
+	// replacements must not change the width of a line
+	input = "│½│æ│"
+	if got := Transliterate(input); len(got) != utf8.RuneCountInString(input) {
+		t.Errorf("Expected %q to keep %v columns, got %q", input, utf8.RuneCountInString(input), got)
+	}
+	for r, replacement := range asciiReplacements {
+		if replacement >= utf8.RuneSelf {
+			t.Errorf("Replacement for %q is not ASCII: %q", r, replacement)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+
+	"github.com/matjam/mecca/ansi"
+)
+
+func runConvert(args []string) int {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	from := fs.String("from", "cp437", "charset of the input files")
+	to := fs.String("to", "cp437", "charset of the output files")
+	width := fs.Int("width", 0, "re-wrap art to this many columns (0 leaves the layout alone)")
+	stripSauce := fs.Bool("strip-sauce", false, "remove SAUCE records")
+	repairSauce := fs.Bool("repair-sauce", false, "correct the size and dimensions in SAUCE records")
+	maxWidth := fs.Int("max-width", 0, "report files wider than this many columns")
+	maxHeight := fs.Int("max-height", 0, "report files taller than this many lines")
+	out := fs.String("out", "", "directory to write converted files to; if empty, files are only checked")
+	prof := addProfileFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: mecca convert [flags] file...\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	opts := ansi.ConvertOptions{
+		Width:       *width,
+		StripSauce:  *stripSauce,
+		RepairSauce: *repairSauce,
+	}
+
+	if opts.Width < 0 || opts.Width > ansi.MAX_CANVAS_WIDTH {
+		fmt.Fprintf(os.Stderr, "mecca convert: -width must be between 0 and %d\n", ansi.MAX_CANVAS_WIDTH)
+		return 2
+	}
+
+	var err error
+	if opts.From, err = ansi.ParseCharset(*from); err != nil {
+		fmt.Fprintf(os.Stderr, "mecca convert: %s\n", err)
+		return 2
+	}
+	if opts.To, err = ansi.ParseCharset(*to); err != nil {
+		fmt.Fprintf(os.Stderr, "mecca convert: %s\n", err)
+		return 2
+	}
+
+	if *out != "" {
+		if err := checkOutputs(fs.Args(), *out); err != nil {
+			fmt.Fprintf(os.Stderr, "mecca convert: %s\n", err)
+			return 2
+		}
+	}
+
+	stop, err := prof.start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mecca convert: %s\n", err)
+		return 1
+	}
+	defer stop()
+
+	status := 0
+	for _, name := range fs.Args() {
+		var ok bool
+		pprof.Do(context.Background(), pprof.Labels("command", "convert", "file", name), func(context.Context) {
+			ok, err = convertFile(name, *out, opts, *maxWidth, *maxHeight)
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "mecca convert: %s: %s\n", name, err)
+			status = 1
+			continue
+		}
+		if !ok {
+			status = 1
+		}
+	}
+
+	return status
+}
+
+// convertFile converts a single file, writing the result into dir if it is
+// set. It returns false if the converted file exceeds the size budget.
+func convertFile(name, dir string, opts ansi.ConvertOptions, maxWidth, maxHeight int) (bool, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return false, err
+	}
+
+	converted, err := ansi.Convert(data, opts)
+	if err != nil {
+		return false, err
+	}
+
+	// measure with the width the art was drawn for, which the converted
+	// output no longer records if its SAUCE record was stripped
+	wrap := opts.Width
+	if _, sauce := ansi.SplitSauce(data); wrap == 0 && sauce != nil {
+		wrap = sauce.Width()
+	}
+
+	w, h, err := ansi.MeasureWrapped(converted, opts.To, wrap)
+	if err != nil {
+		return false, err
+	}
+
+	ok := true
+	if maxWidth > 0 && w > maxWidth {
+		fmt.Printf("%s: width %d exceeds %d\n", name, w, maxWidth)
+		ok = false
+	}
+	if maxHeight > 0 && h > maxHeight {
+		fmt.Printf("%s: height %d exceeds %d\n", name, h, maxHeight)
+		ok = false
+	}
+
+	if dir == "" {
+		return ok, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return false, err
+	}
+
+	return ok, os.WriteFile(outputPath(name, dir), converted, 0644)
+}
+
+func outputPath(name, dir string) string {
+	return filepath.Join(dir, filepath.Base(name))
+}
+
+// checkOutputs makes sure that writing the converted files into dir won't
+// overwrite one of the inputs, or write two inputs with the same name to the
+// same place. It is checked before anything is converted so that a bad set of
+// arguments doesn't leave a half-written output directory behind.
+func checkOutputs(names []string, dir string) error {
+	targets := make(map[string]string, len(names))
+
+	for _, name := range names {
+		target, err := filepath.Abs(outputPath(name, dir))
+		if err != nil {
+			return err
+		}
+
+		if other, ok := targets[target]; ok {
+			return fmt.Errorf("%s and %s would both be written to %s", other, name, target)
+		}
+		targets[target] = name
+
+		in, err := os.Stat(name)
+		if err != nil {
+			// reported when the file is converted
+			continue
+		}
+		if existing, err := os.Stat(target); err == nil && os.SameFile(in, existing) {
+			return fmt.Errorf("%s would be overwritten by its own output", name)
+		}
+	}
+
+	return nil
+}
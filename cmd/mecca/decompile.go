@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"runtime/pprof"
+
+	"github.com/matjam/mecca/ansi"
+)
+
+func runDecompile(args []string) int {
+	fs := flag.NewFlagSet("decompile", flag.ExitOnError)
+	from := fs.String("from", "cp437", "charset of the input file")
+	out := fs.String("out", "", "file to write the MECCA source to; if empty, it is written to stdout")
+	prof := addProfileFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: mecca decompile [flags] file\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	charset, err := ansi.ParseCharset(*from)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mecca decompile: %s\n", err)
+		return 2
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mecca decompile: %s\n", err)
+		return 1
+	}
+
+	stop, err := prof.start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mecca decompile: %s\n", err)
+		return 1
+	}
+	defer stop()
+
+	var source string
+	pprof.Do(context.Background(), pprof.Labels("command", "decompile", "file", fs.Arg(0)), func(context.Context) {
+		source, err = ansi.Decompile(data, charset)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mecca decompile: %s: %s\n", fs.Arg(0), err)
+		return 1
+	}
+
+	if *out == "" {
+		fmt.Print(source)
+		return 0
+	}
+
+	if err := os.WriteFile(*out, []byte(source), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "mecca decompile: %s\n", err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,49 @@
+// Command mecca provides tools for working with MECCA templates and the ANSI
+// art that goes with them.
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+type command struct {
+	run   func(args []string) int
+	usage string
+}
+
+var commands = map[string]command{
+	"convert":   {runConvert, "convert charsets, SAUCE records and widths of art files"},
+	"decompile": {runDecompile, "convert an ANSI art file into MECCA source"},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: mecca <command> [arguments]\n\ncommands:\n")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].usage)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "mecca: unknown command '%s'\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	os.Exit(cmd.run(os.Args[2:]))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+type profileFlags struct {
+	cpu *string
+	mem *string
+}
+
+func addProfileFlags(fs *flag.FlagSet) *profileFlags {
+	return &profileFlags{
+		cpu: fs.String("cpuprofile", "", "write a CPU profile to this file"),
+		mem: fs.String("memprofile", "", "write an allocation profile to this file when done"),
+	}
+}
+
+// start begins CPU profiling if it was requested. The returned function stops
+// it and writes the allocation profile, and must be called before exiting.
+// Work is tagged with pprof labels naming the file being processed, so
+// profiles of a whole art pack can be broken down per file.
+func (p *profileFlags) start() (func(), error) {
+	var cpu *os.File
+
+	if *p.cpu != "" {
+		f, err := os.Create(*p.cpu)
+		if err != nil {
+			return nil, err
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, err
+		}
+		cpu = f
+	}
+
+	stop := func() {
+		if cpu != nil {
+			pprof.StopCPUProfile()
+			cpu.Close()
+		}
+
+		if *p.mem != "" {
+			f, err := os.Create(*p.mem)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "mecca: %s\n", err)
+				return
+			}
+			defer f.Close()
+
+			runtime.GC()
+			if err := pprof.Lookup("allocs").WriteTo(f, 0); err != nil {
+				fmt.Fprintf(os.Stderr, "mecca: %s\n", err)
+			}
+		}
+	}
+
+	return stop, nil
+}
@@ -32,41 +32,43 @@ func (t Token) String() string {
 type lexMode int
 
 type Lexer struct {
-	input        *bufio.Reader // the raw input for the template
-	output       chan Token    // a channel holding the next available Token
-	buffer       string        // a buffer for the current token
-	bufferLine   int           // the line number where the buffer started
-	bufferColumn int           // the column number where the buffer started
-	tokenType    TokenType     // the type of token currently being processed
-	line         int           // the current line number
-	column       int           // the current column number
-	err          error         // the last error encountered
-	done 	   bool          // whether or not we've reached the end of the input
+	input        *bufio.Reader   // the raw input for the template
+	pending      []Token         // tokens produced but not yet returned by Next
+	head         int             // the index of the next pending token to return
+	buffer       strings.Builder // a buffer for the current token
+	bufferLine   int             // the line number where the buffer started
+	bufferColumn int             // the column number where the buffer started
+	tokenType    TokenType       // the type of token currently being processed
+	line         int             // the current line number
+	column       int             // the current column number
+	err          error           // the last error encountered
+	depth        int             // how deeply commands are nested inside the current argument
+	done         bool            // whether or not we've reached the end of the input
 }
 
+// NewLexer returns a Lexer reading from r. The lexer does no work until
+// tokens are requested with Next, so a consumer can stop at any point
+// without leaving anything running.
 func NewLexer(r io.Reader) *Lexer {
 	l := &Lexer{
 		input:        bufio.NewReader(r),
-		output:       make(chan Token),
-		buffer:       "",
 		bufferLine:   1,
 		bufferColumn: 1,
-		tokenType:         TOKEN_TEXT,
+		tokenType:    TOKEN_TEXT,
 		line:         1,
 		column:       1,
 	}
 
-	go func() {
-		l.process()
-	}()
-
 	return l
 }
 
-
-
-// Lex returns the next token from the input.
-func (l *Lexer) Lex() (Token, error) {
+// Next returns the next token from the input, reading only as much of the
+// input as is needed to produce it. Once the input has been exhausted Next
+// returns a TOKEN_EOF token, and then io.EOF on every subsequent call. If the
+// input is malformed or cannot be read, Next returns the error as soon as it is
+// found, discarding any tokens produced from the malformed input, and will keep
+// returning it on subsequent calls.
+func (l *Lexer) Next() (Token, error) {
 	eofToken := Token{
 		Type:   TOKEN_EOF,
 		Value:  "EOF",
@@ -78,45 +80,65 @@ func (l *Lexer) Lex() (Token, error) {
 		return eofToken, l.err
 	}
 
-	t, ok := <-l.output
-	if !ok {
-		return eofToken, errors.New("lexer: output channel closed")
+	for l.head == len(l.pending) {
+		if l.done {
+			return eofToken, io.EOF
+		}
+
+		// everything pending has been consumed, so we can reuse the slice
+		l.pending = l.pending[:0]
+		l.head = 0
+		l.step()
+
+		if l.err != nil {
+			l.pending = l.pending[:0]
+			return eofToken, l.err
+		}
 	}
 
+	t := l.pending[l.head]
+	l.head++
+
 	return t, nil
 }
 
-// process is the entry point for the lexer. It reads the next rune
-// from the input and decides what to do with it. We start in MODE_UNKNOWN and
-// switch to MODE_TEXT or MODE_COMMAND depending on the input. Once we know
-// which mode we're in, we call the appropriate function to process the input.
-//
-// We loop because we don't return until the input is exhausted, at which point
-// we close the output channel and return.
-func (l *Lexer) process() {
-	for !l.done {
-		r, err := l.next()
-		if err != nil || l.err != nil {
-			if l.buffer != "" {
-				l.emit(TOKEN_TEXT)
-			}
-			l.reset().save().appendString("EOF").emit(TOKEN_EOF)
+// Lex returns the next token from the input. It is equivalent to Next.
+func (l *Lexer) Lex() (Token, error) {
+	return l.Next()
+}
 
-			close(l.output)
-			l.done = true
+// step reads the next rune from the input and decides what to do with it. We
+// start in TOKEN_TEXT mode and switch to TOKEN_COMMAND or TOKEN_COMMAND_ARG
+// depending on the input, calling the appropriate function to process it.
+//
+// A single step may emit zero or more tokens; Next keeps stepping until at
+// least one token is pending or the input is exhausted.
+func (l *Lexer) step() {
+	r, err := l.next()
+	if err != nil {
+		if err != io.EOF {
+			l.err = err
 			return
 		}
 
-		// effectively this is a state machine, which switches between modes
-		// depending on the input..
-		switch l.tokenType {
-		case TOKEN_TEXT:
-			l.processTextMode(r)
-		case TOKEN_COMMAND:
-			l.processCommandMode(r)
-		case TOKEN_COMMAND_ARG:
-			l.processCommandArgMode(r)
+		if l.buffer.Len() > 0 {
+			l.emit(TOKEN_TEXT)
 		}
+		l.reset().save().appendString("EOF").emit(TOKEN_EOF)
+
+		l.done = true
+		return
+	}
+
+	// effectively this is a state machine, which switches between modes
+	// depending on the input..
+	switch l.tokenType {
+	case TOKEN_TEXT:
+		l.processTextMode(r)
+	case TOKEN_COMMAND:
+		l.processCommandMode(r)
+	case TOKEN_COMMAND_ARG:
+		l.processCommandArgMode(r)
 	}
 }
 
@@ -149,19 +171,21 @@ func (l *Lexer) start(m TokenType) *Lexer {
 	return l
 }
 
-// emit sends the current token to the output channel
+// emit queues the current token to be returned by Next
 func (l *Lexer) emit(tokenType TokenType) *Lexer {
-	if tokenType == TOKEN_TEXT && l.buffer == "" {
+	value := l.buffer.String()
+
+	if tokenType == TOKEN_TEXT && value == "" {
 		// we don't want to emit empty text tokens
 		return l
 	}
 
 	// depending on the command the tokenType may be different from the current one
 	if tokenType == TOKEN_COMMAND {
-		switch strings.ToLower(l.buffer) {
+		switch strings.ToLower(value) {
 		case "for":
 			tokenType = TOKEN_START_LOOP
-		case "/for":
+		case "/for", "endfor":
 			tokenType = TOKEN_END_LOOP
 		case "if":
 			tokenType = TOKEN_START_COND
@@ -207,36 +231,38 @@ func (l *Lexer) emit(tokenType TokenType) *Lexer {
 			tokenType = TOKEN_LINE_CLEAR
 		case "no":
 			tokenType = TOKEN_NO
+		case "lb":
+			tokenType = TOKEN_LEFT_BRACKET
+		case "rb":
+			tokenType = TOKEN_RIGHT_BRACKET
 		}
 	}
 
-	l.output <- Token{
+	l.pending = append(l.pending, Token{
 		Type:   tokenType,
-		Value:  l.buffer,
+		Value:  value,
 		Line:   l.bufferLine,
 		Column: l.bufferColumn,
-	}
+	})
 
 	return l
 }
 
 func (l *Lexer) append(r rune) *Lexer {
-	l.buffer += string(r)
+	l.buffer.WriteRune(r)
 
 	return l
 }
 
 func (l *Lexer) appendString(s string) *Lexer {
-	for _, r := range s {
-		l.append(r)
-	}
+	l.buffer.WriteString(s)
 
 	return l
 }
 
 // reset the buffer
 func (l *Lexer) reset() *Lexer {
-	l.buffer = ""
+	l.buffer.Reset()
 
 	return l
 }
@@ -253,7 +279,9 @@ func (l *Lexer) save() *Lexer {
 func (l *Lexer) peek() (rune, error) {
 	r, _, err := l.input.ReadRune()
 	if err != nil {
-		l.err = err
+		if err != io.EOF {
+			l.err = err
+		}
 		return 0, err
 	}
 
@@ -299,7 +327,7 @@ func (l *Lexer) processTextMode(r rune) {
 		l.reset().save().append('[').emit(TOKEN_COMMAND_START).nextColumn()
 		l.reset().start(TOKEN_COMMAND)
 	default:
-		if l.buffer == "" {
+		if l.buffer.Len() == 0 {
 			l.save()
 		}
 		l.append(r).nextColumn()
@@ -316,28 +344,58 @@ func (l *Lexer) processCommandMode(r rune) {
 		l.emit(TOKEN_COMMAND).nextColumn()
 		l.reset().save().start(TOKEN_COMMAND_ARG)
 	case '\n':
-		l.err = errors.New(fmt.Sprintf("Unexpected newline in command '%s' at %v:%v", l.buffer, l.line, l.column))
+		l.err = errors.New(fmt.Sprintf("Unexpected newline in command '%s' at %v:%v", l.buffer.String(), l.line, l.column))
 	default:
-		if l.buffer == "" {
+		if l.buffer.Len() == 0 {
 			l.save()
 		}
 		l.append(r).nextColumn()
 	}
 }
 
-// we only care about ']' and ' ' in command arg mode. Everything else is
+// we only care about '[', ']' and ' ' in command arg mode. Everything else is
 // appended to the buffer.
+//
+// An argument may contain nested commands, such as the [width] in
+// "[line [width] -]". These are kept verbatim in the argument, brackets and
+// spaces included, so that they can be resolved before the outer command runs.
+// A "[[" in an argument is kept as-is so that it remains distinguishable from
+// a nested command.
 func (l *Lexer) processCommandArgMode(r rune) {
 	switch r {
+	case '[':
+		if l.buffer.Len() == 0 {
+			l.save()
+		}
+
+		if next, err := l.peek(); err == nil && next == '[' {
+			l.next()
+			l.appendString("[[").nextColumn().nextColumn()
+			return
+		}
+
+		l.append('[').nextColumn()
+		l.depth++
 	case ']':
+		if l.depth > 0 {
+			l.append(']').nextColumn()
+			l.depth--
+			return
+		}
+
 		l.emit(TOKEN_COMMAND_ARG)
 		l.reset().save().append(']').emit(TOKEN_COMMAND_END).nextColumn()
 		l.reset().save().start(TOKEN_TEXT)
 	case ' ':
+		if l.depth > 0 {
+			l.append(' ').nextColumn()
+			return
+		}
+
 		l.emit(TOKEN_COMMAND_ARG).nextColumn()
 		l.reset().append(' ').reset().save()
 	default:
-		if l.buffer == "" {
+		if l.buffer.Len() == 0 {
 			l.save()
 		}
 		l.append(r).nextColumn()
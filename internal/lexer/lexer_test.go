@@ -58,7 +58,8 @@ func TestLexerUnexpectedNewline(t *testing.T) {
 	expectedResults := []result{
 		{Token{Type: TOKEN_TEXT, Value: "Hello a ", Line: 1, Column: 1}, false},
 		{Token{Type: TOKEN_COMMAND_START, Value: "[", Line: 1, Column: 9}, false},
-		{Token{Type: TOKEN_TEXT, Value: "command", Line: 1, Column: 10}, false},
+		// the partial command is discarded rather than returned as text
+		{Token{Type: TOKEN_TEXT, Value: "", Line: 0, Column: 0}, true},
 		{Token{Type: TOKEN_TEXT, Value: "", Line: 0, Column: 0}, true},
 	}
 
@@ -231,3 +232,243 @@ func TestLexerWithFixture(t *testing.T) {
 		}
 	}
 }
+
+// benchmarkTemplate builds a template of at least size bytes by repeating
+// chunk, so each benchmark lexes roughly the same amount of input.
+func benchmarkTemplate(chunk string, size int) string {
+	var sb strings.Builder
+	for sb.Len() < size {
+		sb.WriteString(chunk)
+	}
+	return sb.String()
+}
+
+func benchmarkLexer(b *testing.B, input string) {
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l := NewLexer(strings.NewReader(input))
+		for {
+			token, err := l.Lex()
+			if err != nil {
+				b.Fatalf("Error lexing: %s", err)
+			}
+			if token.Type == TOKEN_EOF {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkLexerText(b *testing.B) {
+	chunk := "Just some plain text without any commands in it at all, repeated.\n"
+	benchmarkLexer(b, benchmarkTemplate(chunk, 100*1024))
+}
+
+func BenchmarkLexerColorHeavy(b *testing.B) {
+	chunk := "[fg red]R[fg green]G[fg blue]B[bg black][bold]bold[reset] [fg bright_yellow][bg blue]text[reset]\n"
+	benchmarkLexer(b, benchmarkTemplate(chunk, 100*1024))
+}
+
+func BenchmarkLexerIncludeHeavy(b *testing.B) {
+	chunk := "[include header.mec]\n[include menus/main.mec]\n[include footer.mec]\n"
+	benchmarkLexer(b, benchmarkTemplate(chunk, 100*1024))
+}
+
+func BenchmarkLexerInteractive(b *testing.B) {
+	chunk := "[position 10 10][fg white]1. [fg green]Read Messages [[R]\n[input]\n[if choice][/if]\n"
+	benchmarkLexer(b, benchmarkTemplate(chunk, 100*1024))
+}
+
+func TestLexerNextAfterEOF(t *testing.T) {
+	input := "[command]"
+	r := strings.NewReader(input)
+	l := NewLexer(r)
+
+	expected := []Token{
+		{Type: TOKEN_COMMAND_START, Value: "[", Line: 1, Column: 1},
+		{Type: TOKEN_COMMAND, Value: "command", Line: 1, Column: 2},
+		{Type: TOKEN_COMMAND_END, Value: "]", Line: 1, Column: 9},
+		{Type: TOKEN_EOF, Value: "EOF", Line: 1, Column: 10},
+	}
+
+	for _, expectedToken := range expected {
+		token, err := l.Next()
+		if err != nil {
+			t.Errorf("Error lexing: %s", err)
+		}
+
+		if token != expectedToken {
+			t.Errorf("Expected %v got %v\n", expectedToken, token)
+		}
+	}
+
+	// once the EOF token has been returned, Next keeps returning EOF
+	for i := 0; i < 2; i++ {
+		token, err := l.Next()
+		if err != io.EOF {
+			t.Errorf("Expected io.EOF, got %v", err)
+		}
+		if token.Type != TOKEN_EOF {
+			t.Errorf("Expected EOF token, got %v", token)
+		}
+	}
+}
+
+func TestLexerLoop(t *testing.T) {
+	//        123456789012345678901234567890123456789012345678901234567890
+	input := "[for msg in messages][msg.subject][endfor][/for]"
+	r := strings.NewReader(input)
+	l := NewLexer(r)
+
+	expected := []Token{
+		{Type: TOKEN_COMMAND_START, Value: "[", Line: 1, Column: 1},
+		{Type: TOKEN_START_LOOP, Value: "for", Line: 1, Column: 2},
+		{Type: TOKEN_COMMAND_ARG, Value: "msg", Line: 1, Column: 6},
+		{Type: TOKEN_COMMAND_ARG, Value: "in", Line: 1, Column: 10},
+		{Type: TOKEN_COMMAND_ARG, Value: "messages", Line: 1, Column: 13},
+		{Type: TOKEN_COMMAND_END, Value: "]", Line: 1, Column: 21},
+		{Type: TOKEN_COMMAND_START, Value: "[", Line: 1, Column: 22},
+		{Type: TOKEN_COMMAND, Value: "msg.subject", Line: 1, Column: 23},
+		{Type: TOKEN_COMMAND_END, Value: "]", Line: 1, Column: 34},
+		{Type: TOKEN_COMMAND_START, Value: "[", Line: 1, Column: 35},
+		{Type: TOKEN_END_LOOP, Value: "endfor", Line: 1, Column: 36},
+		{Type: TOKEN_COMMAND_END, Value: "]", Line: 1, Column: 42},
+		{Type: TOKEN_COMMAND_START, Value: "[", Line: 1, Column: 43},
+		{Type: TOKEN_END_LOOP, Value: "/for", Line: 1, Column: 44},
+		{Type: TOKEN_COMMAND_END, Value: "]", Line: 1, Column: 48},
+		{Type: TOKEN_EOF, Value: "EOF", Line: 1, Column: 49},
+	}
+
+	for _, expectedToken := range expected {
+		token, err := l.Next()
+		if err != nil {
+			t.Errorf("Error lexing: %s", err)
+			break
+		}
+
+		if token != expectedToken {
+			t.Errorf("Expected %v got %v\n", expectedToken, token)
+		}
+	}
+}
+
+func TestLexerNestedCommandArgs(t *testing.T) {
+	//        123456789012345678901234567890123456789012345678901234567890
+	input := "[line [width] -][include [theme name]/menu.mec][say [[x]"
+	r := strings.NewReader(input)
+	l := NewLexer(r)
+
+	expected := []Token{
+		{Type: TOKEN_COMMAND_START, Value: "[", Line: 1, Column: 1},
+		{Type: TOKEN_COMMAND, Value: "line", Line: 1, Column: 2},
+		{Type: TOKEN_COMMAND_ARG, Value: "[width]", Line: 1, Column: 7},
+		{Type: TOKEN_COMMAND_ARG, Value: "-", Line: 1, Column: 15},
+		{Type: TOKEN_COMMAND_END, Value: "]", Line: 1, Column: 16},
+		{Type: TOKEN_COMMAND_START, Value: "[", Line: 1, Column: 17},
+		{Type: TOKEN_COMMAND, Value: "include", Line: 1, Column: 18},
+		{Type: TOKEN_COMMAND_ARG, Value: "[theme name]/menu.mec", Line: 1, Column: 26},
+		{Type: TOKEN_COMMAND_END, Value: "]", Line: 1, Column: 47},
+		{Type: TOKEN_COMMAND_START, Value: "[", Line: 1, Column: 48},
+		{Type: TOKEN_COMMAND, Value: "say", Line: 1, Column: 49},
+		{Type: TOKEN_COMMAND_ARG, Value: "[[x", Line: 1, Column: 53},
+		{Type: TOKEN_COMMAND_END, Value: "]", Line: 1, Column: 56},
+		{Type: TOKEN_EOF, Value: "EOF", Line: 1, Column: 57},
+	}
+
+	for _, expectedToken := range expected {
+		token, err := l.Next()
+		if err != nil {
+			t.Errorf("Error lexing: %s", err)
+			break
+		}
+
+		if token != expectedToken {
+			t.Errorf("Expected %v got %v\n", expectedToken, token)
+		}
+	}
+}
+
+func TestLexerBracketCommands(t *testing.T) {
+	//        123456789012345678901234567890123456789012345678901234567890
+	input := "[lb]Y,n[rb]"
+	r := strings.NewReader(input)
+	l := NewLexer(r)
+
+	expected := []Token{
+		{Type: TOKEN_COMMAND_START, Value: "[", Line: 1, Column: 1},
+		{Type: TOKEN_LEFT_BRACKET, Value: "lb", Line: 1, Column: 2},
+		{Type: TOKEN_COMMAND_END, Value: "]", Line: 1, Column: 4},
+		{Type: TOKEN_TEXT, Value: "Y,n", Line: 1, Column: 5},
+		{Type: TOKEN_COMMAND_START, Value: "[", Line: 1, Column: 8},
+		{Type: TOKEN_RIGHT_BRACKET, Value: "rb", Line: 1, Column: 9},
+		{Type: TOKEN_COMMAND_END, Value: "]", Line: 1, Column: 11},
+		{Type: TOKEN_EOF, Value: "EOF", Line: 1, Column: 12},
+	}
+
+	for _, expectedToken := range expected {
+		token, err := l.Next()
+		if err != nil {
+			t.Errorf("Error lexing: %s", err)
+			break
+		}
+
+		if token != expectedToken {
+			t.Errorf("Expected %v got %v\n", expectedToken, token)
+		}
+	}
+}
+
+func TestLexerNextTrailingBracket(t *testing.T) {
+	input := "abc["
+	r := strings.NewReader(input)
+	l := NewLexer(r)
+
+	expected := []Token{
+		{Type: TOKEN_TEXT, Value: "abc[", Line: 1, Column: 1},
+		{Type: TOKEN_EOF, Value: "EOF", Line: 1, Column: 5},
+	}
+
+	for _, expectedToken := range expected {
+		token, err := l.Next()
+		if err != nil {
+			t.Errorf("Error lexing: %s", err)
+		}
+
+		if token != expectedToken {
+			t.Errorf("Expected %v got %v\n", expectedToken, token)
+		}
+	}
+
+	if token, err := l.Next(); err != io.EOF || token.Type != TOKEN_EOF {
+		t.Errorf("Expected EOF token and io.EOF, got %v %v", token, err)
+	}
+}
+
+func TestLexerNextNewlineInCommand(t *testing.T) {
+	input := "[foo\nbar]"
+	r := strings.NewReader(input)
+	l := NewLexer(r)
+
+	token, err := l.Next()
+	if err != nil {
+		t.Errorf("Error lexing: %s", err)
+	}
+	if expected := (Token{Type: TOKEN_COMMAND_START, Value: "[", Line: 1, Column: 1}); token != expected {
+		t.Errorf("Expected %v got %v\n", expected, token)
+	}
+
+	// the error is returned straight away, without the partial command, and
+	// keeps being returned
+	for i := 0; i < 2; i++ {
+		token, err := l.Next()
+		if err == nil || err == io.EOF {
+			t.Errorf("Expected a lexing error, got %v %v", token, err)
+		}
+		if token.Type != TOKEN_EOF {
+			t.Errorf("Expected EOF token, got %v", token)
+		}
+	}
+}
@@ -37,4 +37,6 @@ const (
 	TOKEN_SCREEN_CLEAR
 	TOKEN_LINE_CLEAR
 	TOKEN_NO
+	TOKEN_LEFT_BRACKET
+	TOKEN_RIGHT_BRACKET
 )
@@ -40,11 +40,14 @@ func _() {
 	_ = x[TOKEN_CURSOR_SET_POSITION-29]
 	_ = x[TOKEN_SCREEN_CLEAR-30]
 	_ = x[TOKEN_LINE_CLEAR-31]
+	_ = x[TOKEN_NO-32]
+	_ = x[TOKEN_LEFT_BRACKET-33]
+	_ = x[TOKEN_RIGHT_BRACKET-34]
 }
 
-const _TokenType_name = "TEXTNLCOMMAND_STARTCOMMAND_ENDCOMMANDCOMMAND_ARGFUNCSTART_LOOPEND_LOOPSTART_CONDEND_CONDEOFRESETBOLDFAINTITALICUNDERLINEBLINK_SLOWBLINK_RAPIDREVERSECROSSED_OUTFGBGCURSOR_UPCURSOR_DOWNCURSOR_FORWARDCURSOR_BACKWARDCURSOR_NEXT_LINECURSOR_PREV_LINECURSOR_SET_POSITIONSCREEN_CLEARLINE_CLEAR"
+const _TokenType_name = "TEXTNLCOMMAND_STARTCOMMAND_ENDCOMMANDCOMMAND_ARGFUNCSTART_LOOPEND_LOOPSTART_CONDEND_CONDEOFRESETBOLDFAINTITALICUNDERLINEBLINK_SLOWBLINK_RAPIDREVERSECROSSED_OUTFGBGCURSOR_UPCURSOR_DOWNCURSOR_FORWARDCURSOR_BACKWARDCURSOR_NEXT_LINECURSOR_PREV_LINECURSOR_SET_POSITIONSCREEN_CLEARLINE_CLEARNOLEFT_BRACKETRIGHT_BRACKET"
 
-var _TokenType_index = [...]uint16{0, 4, 6, 19, 30, 37, 48, 52, 62, 70, 80, 88, 91, 96, 100, 105, 111, 120, 130, 141, 148, 159, 161, 163, 172, 183, 197, 212, 228, 244, 263, 275, 285}
+var _TokenType_index = [...]uint16{0, 4, 6, 19, 30, 37, 48, 52, 62, 70, 80, 88, 91, 96, 100, 105, 111, 120, 130, 141, 148, 159, 161, 163, 172, 183, 197, 212, 228, 244, 263, 275, 285, 287, 299, 312}
 
 func (i TokenType) String() string {
 	if i < 0 || i >= TokenType(len(_TokenType_index)-1) {